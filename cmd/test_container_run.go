@@ -1,14 +1,29 @@
 package cmd
 
 import (
+	"crypto/sha1"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/syntasso/kratix-cli/pkg/diff"
+	"github.com/syntasso/kratix-cli/pkg/imagebuilder"
+	"github.com/syntasso/kratix-cli/pkg/testcase"
+	"github.com/syntasso/kratix-cli/pkg/testreport"
+	"github.com/syntasso/kratix-cli/pkg/testrunner"
 )
 
 var testContainerRunCmd = &cobra.Command{
@@ -18,18 +33,36 @@ var testContainerRunCmd = &cobra.Command{
   kratix test container run resource/configure/instance/syntasso-postgres-resource
 
   # run specific testcases for a container image
-  kratix test container run resource/configure/instance/syntasso-postgres-resource --testcases test1,test2,test3`,
+  kratix test container run resource/configure/instance/syntasso-postgres-resource --testcases test1,test2,test3
+
+  # run testcases matching a glob, tagged "smoke", skipping any "slow-*" testcase
+  kratix test container run resource/configure/instance/syntasso-postgres-resource --testcases 'crud-*' --tags smoke --skip 'slow-*'`,
 	RunE: TestContainerRun,
 	Args: cobra.ExactArgs(1),
 }
 
-var testcaseNames, command, kindCluster string
+var testcaseNames, tagsFilter, skipPattern, command, kindCluster, kubeconfigPath, outputFormat, builderName, registry, platform string
+var updateGolden, failFast bool
+var parallelism int
+var reportSpecs, buildArgs []string
 
 func init() {
 	testContainerCmd.AddCommand(testContainerRunCmd)
-	testContainerRunCmd.Flags().StringVarP(&testcaseNames, "testcases", "t", "", "Comma-separated list of testcases to run")
+	testContainerRunCmd.Flags().StringVarP(&testcaseNames, "testcases", "t", "", "Comma-separated list of testcases to run; entries may be glob patterns (e.g. 'crud-*,failure-*')")
+	testContainerRunCmd.Flags().StringVar(&tagsFilter, "tags", "", "Comma-separated list of tags to run; a testcase is included if its testcase.yaml tags intersect this list")
+	testContainerRunCmd.Flags().StringVar(&skipPattern, "skip", "", "Comma-separated list of glob patterns for testcases to exclude, applied after --testcases/--tags")
 	testContainerRunCmd.Flags().StringVarP(&command, "command", "c", "", "Command to start the image with")
 	testContainerRunCmd.Flags().StringVarP(&kindCluster, "kind-cluster", "k", "", "Name of the KinD cluster to use")
+	testContainerRunCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to a kubeconfig file to stage into the container's input, for testcases that talk to the KinD cluster")
+	testContainerRunCmd.Flags().BoolVarP(&updateGolden, "update", "u", false, "Rewrite after/ golden files from the observed container output instead of failing on a mismatch")
+	testContainerRunCmd.Flags().StringVar(&outputFormat, "output", "text", "Output format for test results: text or json")
+	testContainerRunCmd.Flags().IntVarP(&parallelism, "parallel", "p", runtime.NumCPU(), "Number of testcases to run concurrently")
+	testContainerRunCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop starting new testcases as soon as one fails")
+	testContainerRunCmd.Flags().StringArrayVar(&reportSpecs, "report", nil, "Write a test report in the given format, as format=path (e.g. junit=report.xml, json=report.json); may be repeated")
+	testContainerRunCmd.Flags().StringVar(&builderName, "builder", "docker", "Image builder to use: docker, podman, buildah, or kaniko")
+	testContainerRunCmd.Flags().StringVar(&registry, "registry", "", "host:port of an image registry to push to; required when --builder=kaniko")
+	testContainerRunCmd.Flags().StringArrayVar(&buildArgs, "build-arg", nil, "Set a build-time variable, as KEY=VALUE; may be repeated")
+	testContainerRunCmd.Flags().StringVar(&platform, "platform", "", "Target platform for the build, e.g. linux/amd64")
 }
 
 func TestContainerRun(cmd *cobra.Command, args []string) error {
@@ -56,7 +89,7 @@ func TestContainerRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	testcaseDirs, err := getTestcaseDirs(imageTestDir, testcaseNames)
+	testcaseDirs, err := getTestcaseDirs(imageTestDir, testcaseNames, tagsFilter, skipPattern)
 	if err != nil {
 		return err
 	}
@@ -66,45 +99,185 @@ func TestContainerRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if testcaseNames == "" {
-		fmt.Println("\n\033[35mRunning all container testcases...\033[0m")
-	} else {
-		fmt.Printf("\n\033[35mRunning testcases:\033[0m %s\n", testcaseNames)
-	}
-
-	optionalNewline := ""
-	if verbose {
-		optionalNewline = "\n"
+	// In JSON mode, every human-readable banner and per-testcase line is
+	// suppressed so callers can pipe stdout straight into a JSON consumer.
+	// --verbose output (build, progress and container output) still goes to
+	// stdout, so stdout is pure JSON only when --verbose is off.
+	if outputFormat != "json" {
+		if testcaseNames == "" {
+			fmt.Println("\n\033[35mRunning all container testcases...\033[0m")
+		} else {
+			fmt.Printf("\n\033[35mRunning testcases:\033[0m %s\n", testcaseNames)
+		}
 	}
 
-	for _, testcaseDir := range testcaseDirs {
-		fmt.Printf("\033[35mRunning testcase:\033[0m %s...%s", path.Base(testcaseDir), optionalNewline)
+	results := runTestcasesConcurrently(testcaseDirs, imageName)
 
-		err = runTestcase(testcaseDir, imageName)
-		if err != nil {
-			fmt.Printf("\033[31m❌\n  Testcase failed: %s\033[0m\n", err)
-			continue
+	if outputFormat == "json" {
+		if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			return err
 		}
+	}
 
-		optionalTestcasePassed := ""
-		if verbose {
-			optionalTestcasePassed = "Testcase passed "
+	for _, spec := range reportSpecs {
+		if err := writeReport(results, spec); err != nil {
+			return err
 		}
+	}
 
-		fmt.Printf("\033[32m%s✅\033[0m\n", optionalTestcasePassed)
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil || !result.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d testcase(s) failed", failed, len(results))
 	}
 
 	return nil
 }
 
-func kindLoadImage(image, clusterName string) error {
-	printfVerbose("Loading image %q into KinD cluster %q...", image, clusterName)
-	cmd := exec.Command("kind", "load", "docker-image", image, "--name", clusterName)
-	if verbose {
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+// testcaseResult is the outcome of running a single testcase, independent
+// of whether results are rendered as colored text or JSON.
+type testcaseResult struct {
+	Name     string                   `json:"name"`
+	Passed   bool                     `json:"passed"`
+	Updated  bool                     `json:"updated,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+	Diffs    map[string]*diff.DirDiff `json:"diffs,omitempty"`
+	Duration time.Duration            `json:"durationNs,omitempty"`
+
+	// Err holds an infrastructure failure (docker/container error) as
+	// opposed to a testcase comparison failure, which is instead recorded
+	// via Passed/Error/Diffs above.
+	Err error `json:"-"`
+}
+
+// runTestcasesConcurrently runs each of testcaseDirs against imageName using
+// a pool of --parallel workers, each in its own isolated tempdir and
+// container. If --fail-fast is set, workers stop picking up new testcases
+// as soon as one result comes back failed or errored.
+func runTestcasesConcurrently(testcaseDirs []string, imageName string) []*testcaseResult {
+	workers := parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(testcaseDirs) {
+		workers = len(testcaseDirs)
+	}
+
+	type job struct {
+		index int
+		dir   string
+	}
+
+	jobs := make(chan job)
+	results := make([]*testcaseResult, len(testcaseDirs))
+
+	var stop atomic.Bool
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if stop.Load() {
+					results[j.index] = &testcaseResult{Name: path.Base(j.dir), Error: "skipped: --fail-fast"}
+					continue
+				}
+
+				containerName := testcaseContainerName(j.dir)
+				result, err := runTestcase(j.dir, imageName, containerName)
+				if err != nil {
+					result = &testcaseResult{Name: path.Base(j.dir), Err: err}
+				}
+				results[j.index] = result
+
+				mu.Lock()
+				printTestcaseResult(result)
+				mu.Unlock()
+
+				if failFast && (err != nil || !result.Passed) {
+					stop.Store(true)
+				}
+			}
+		}()
+	}
+
+	for i, dir := range testcaseDirs {
+		jobs <- job{index: i, dir: dir}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// testcaseContainerName derives a stable, readable container name from a
+// testcase's directory so that concurrent runs are distinguishable with
+// `docker ps`, e.g. when debugging a hung testcase.
+func testcaseContainerName(testcaseDir string) string {
+	sum := sha1.Sum([]byte(testcaseDir))
+	return fmt.Sprintf("kratix-test-%x", sum[:6])
+}
+
+// printTestcaseResult prints the colored pass/fail line for a single
+// testcase. It is a no-op in JSON output mode, where results are instead
+// emitted once, in full, at the end of the run.
+func printTestcaseResult(result *testcaseResult) {
+	if outputFormat == "json" {
+		return
+	}
+
+	if result.Err != nil {
+		fmt.Printf("\033[35mTestcase %s:\033[0m \033[31m❌ %s\033[0m\n", result.Name, result.Err)
+		return
+	}
+	if !result.Passed {
+		fmt.Printf("\033[35mTestcase %s:\033[0m \033[31m❌\n  Testcase failed: %s\033[0m\n", result.Name, result.Error)
+		return
+	}
+
+	status := "✅"
+	if result.Updated {
+		status = "✅ (golden files updated)"
+	}
+	fmt.Printf("\033[35mTestcase %s:\033[0m \033[32m%s\033[0m\n", result.Name, status)
+}
+
+// writeReport renders results in the format named by spec ("format=path",
+// e.g. "junit=report.xml") and writes it to the given path.
+func writeReport(results []*testcaseResult, spec string) error {
+	format, reportPath, err := testreport.ParseSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	report := &testreport.Report{SuiteName: "kratix-test-container-run"}
+	for _, result := range results {
+		errMsg := result.Error
+		if result.Err != nil {
+			errMsg = result.Err.Error()
+		}
+		report.Cases = append(report.Cases, testreport.CaseResult{
+			Name:     result.Name,
+			Passed:   result.Passed,
+			Error:    errMsg,
+			Duration: result.Duration,
+		})
+	}
+
+	switch format {
+	case "junit":
+		return report.WriteJUnit(reportPath)
+	case "json":
+		return report.WriteJSON(reportPath)
+	default:
+		return fmt.Errorf("unsupported --report format %q, expected junit or json", format)
 	}
-	return cmd.Run()
 }
 
 func kindCheckCluster(clusterName string) error {
@@ -128,25 +301,121 @@ func kindCheckCluster(clusterName string) error {
 	return fmt.Errorf("kind cluster %q does not exist", clusterName)
 }
 
-func getTestcaseDirs(imageDir, testcaseNames string) ([]string, error) {
-	if testcaseNames == "" {
-		return getDirs(imageDir)
+// testcaseIndexEntry is a testcase's directory alongside the fields its
+// testcase.yaml is selected on, precomputed once per run so that each
+// testcase.yaml is read only once and applying --testcases/--tags/--skip is
+// a single pass over the corpus (each entry is checked against every given
+// pattern), even across hundreds of testcases.
+type testcaseIndexEntry struct {
+	name string
+	dir  string
+	tags []string
+}
+
+// getTestcaseDirs resolves the testcase directories under imageDir selected
+// by testcaseNames (a comma-separated list of exact names or glob
+// patterns), further filtered to those tagged with at least one of tags (a
+// comma-separated list, matched against each testcase.yaml's tags field),
+// and finally excluding any matched by a skip glob pattern.
+func getTestcaseDirs(imageDir, testcaseNames, tags, skip string) ([]string, error) {
+	dirs, err := getDirs(imageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make([]testcaseIndexEntry, 0, len(dirs))
+	for _, dir := range dirs {
+		spec, err := testcase.Load(dir)
+		if err != nil {
+			return nil, err
+		}
+		index = append(index, testcaseIndexEntry{name: path.Base(dir), dir: dir, tags: spec.Tags})
+	}
+
+	var namePatterns, tagFilters, skipPatterns []string
+	if testcaseNames != "" {
+		namePatterns = strings.Split(testcaseNames, ",")
 	}
+	if tags != "" {
+		tagFilters = strings.Split(tags, ",")
+	}
+	if skip != "" {
+		skipPatterns = strings.Split(skip, ",")
+	}
+
+	matchedPattern := make(map[string]bool, len(namePatterns))
+	var testcaseDirs []string
+
+	for _, entry := range index {
+		if len(namePatterns) > 0 {
+			matched := false
+			for _, pattern := range namePatterns {
+				ok, err := filepath.Match(pattern, entry.name)
+				if err != nil {
+					return nil, fmt.Errorf("invalid --testcases pattern %q: %w", pattern, err)
+				}
+				if ok {
+					matched = true
+					matchedPattern[pattern] = true
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		if len(tagFilters) > 0 && !hasAnyTag(entry.tags, tagFilters) {
+			continue
+		}
 
-	testcaseNamesList := strings.Split(testcaseNames, ",")
-	testcaseDirs := make([]string, 0, len(testcaseNamesList))
+		if matchesAnyPattern(skipPatterns, entry.name) {
+			continue
+		}
 
-	for _, testcaseName := range testcaseNamesList {
-		testcaseDir := path.Join(imageDir, testcaseName)
-		if _, err := os.Stat(testcaseDir); os.IsNotExist(err) {
-			return nil, fmt.Errorf("testcase directory %q does not exist", testcaseDir)
+		testcaseDirs = append(testcaseDirs, entry.dir)
+	}
+
+	for _, pattern := range namePatterns {
+		if !isGlobPattern(pattern) && !matchedPattern[pattern] {
+			return nil, fmt.Errorf("testcase directory %q does not exist", path.Join(imageDir, pattern))
 		}
-		testcaseDirs = append(testcaseDirs, testcaseDir)
 	}
 
 	return testcaseDirs, nil
 }
 
+// isGlobPattern reports whether pattern contains any filepath.Match
+// metacharacters, so that a literal name with no matches is still reported
+// as a missing testcase directory rather than silently selecting nothing.
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// hasAnyTag reports whether have and want share at least one tag.
+func hasAnyTag(have, want []string) bool {
+	haveSet := make(map[string]bool, len(have))
+	for _, t := range have {
+		haveSet[t] = true
+	}
+	for _, t := range want {
+		if haveSet[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAnyPattern reports whether name matches any of the given glob
+// patterns.
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func getDirs(dir string) ([]string, error) {
 	var dirs []string
 
@@ -164,158 +433,243 @@ func getDirs(dir string) ([]string, error) {
 	return dirs, nil
 }
 
-func runTestcase(testcaseDir, image string) error {
-	// Copy the before/ files to a temporary directory
-	beforeDir := path.Join(testcaseDir, "before")
-	// get a tempdir in /tmp
-	tmpdir := path.Join(os.TempDir(), fmt.Sprintf("kratix-test-%s-%d", path.Base(testcaseDir), time.Now().Unix()))
-	err := os.MkdirAll(tmpdir, os.ModePerm)
+func runTestcase(testcaseDir, image, containerName string) (*testcaseResult, error) {
+	name := path.Base(testcaseDir)
+	result := &testcaseResult{Name: name}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	spec, err := testcase.Load(testcaseDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	printfVerbose("Copying before/ files to temporary directory %s...\n", tmpdir)
-
-	// copy the before/ files to the tempdir
-	err = copyDir(beforeDir, tmpdir)
+	timeout, err := spec.TimeoutDuration()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("parsing timeout: %w", err)
 	}
 
-	homedir, err := os.UserHomeDir()
+	requestObject, err := spec.RequestObject(testcaseDir)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("resolving kratixRequest: %w", err)
 	}
 
-	volumes := []string{
-		path.Join(tmpdir, "output") + ":/kratix/output",
-		path.Join(tmpdir, "input") + ":/kratix/input",
-		path.Join(tmpdir, "metadata") + ":/kratix/metadata",
+	beforeDir := path.Join(testcaseDir, "before")
+	tmpdir, err := os.MkdirTemp("", fmt.Sprintf("kratix-test-%s-", name))
+	if err != nil {
+		return nil, err
 	}
-	if kindCluster != "" {
-		volumes = append(volumes, path.Join(homedir, ".kube", "config")+":/root/.kube/config")
+
+	kubeconfig, err := resolveKubeconfigPath()
+	if err != nil {
+		return nil, err
 	}
 
-	cmdArgs := []string{
-		"run",
-		"--rm",
-		"--network=host",
+	printfVerbose("Streaming before/ files into container and running image %q...\n", image)
+
+	stdout, stderr := os.Stdout, os.Stderr
+	if !verbose {
+		stdout, stderr = nil, nil
 	}
-	for _, volume := range volumes {
-		cmdArgs = append(cmdArgs, "--volume", volume)
+
+	runResult, err := testrunner.Run(testrunner.Params{
+		Image:          image,
+		Before:         beforeDir,
+		Dest:           tmpdir,
+		KubeconfigPath: kubeconfig,
+		ContainerName:  containerName,
+		Env:            spec.Env,
+		Command:        spec.Command,
+		Args:           spec.Args,
+		RequestObject:  requestObject,
+		Timeout:        timeout,
+		Stdout:         stdout,
+		Stderr:         stderr,
+	})
+	if err != nil {
+		return nil, err
 	}
-	cmdArgs = append(cmdArgs, image)
 
-	// Run the container image, mounting the temporary directory
-	// TODO: Extract into a function
-	runner := exec.Command("docker", cmdArgs...)
+	wantExitCode := spec.ExpectExitCode
+	if runResult.ExitCode != wantExitCode {
+		result.Error = fmt.Sprintf("container exited with code %d, expected %d", runResult.ExitCode, wantExitCode)
+		return result, nil
+	}
 
-	if verbose {
-		runner.Stdout = os.Stdout
-		runner.Stderr = os.Stderr
+	if mismatch, err := expectationMismatch("stdout", spec.ExpectStdoutContains, runResult.Stdout); err != nil {
+		return nil, err
+	} else if mismatch != "" {
+		result.Error = mismatch
+		return result, nil
 	}
-	err = runner.Run()
-	if err != nil {
-		return err
+	if mismatch, err := expectationMismatch("stderr", spec.ExpectStderrContains, runResult.Stderr); err != nil {
+		return nil, err
+	} else if mismatch != "" {
+		result.Error = mismatch
+		return result, nil
 	}
 
 	afterDir := path.Join(testcaseDir, "after")
 	printfVerbose("Checking output against after/ files...\n")
 
+	diffs := make(map[string]*diff.DirDiff)
 	for _, dir := range []string{"metadata", "output"} {
-		err = compareDirs(path.Join(tmpdir, dir), path.Join(afterDir, dir))
+		dirDiff, err := diff.CompareDirs(path.Join(tmpdir, dir), path.Join(afterDir, dir), spec.SkipCompare)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if !dirDiff.Empty() {
+			diffs[dir] = dirDiff
 		}
 	}
 
-	return nil
+	if len(diffs) == 0 {
+		result.Passed = true
+		return result, nil
+	}
+
+	if updateGolden {
+		if err := updateGoldenFiles(tmpdir, afterDir); err != nil {
+			return nil, err
+		}
+		result.Passed = true
+		result.Updated = true
+		return result, nil
+	}
+
+	result.Diffs = diffs
+	result.Error = summarizeDiffs(diffs)
+	return result, nil
 }
 
-func compareDirs(dir1, dir2 string) error {
-	entries1, err := os.ReadDir(dir1)
-	if err != nil {
-		return err
+// expectationMismatch checks output against the regular expression pattern
+// configured for the given stream ("stdout" or "stderr"), returning a
+// human-readable mismatch description, or "" if pattern is unset or matches.
+func expectationMismatch(stream, pattern, output string) (string, error) {
+	if pattern == "" {
+		return "", nil
 	}
 
-	entries2, err := os.ReadDir(dir2)
+	matched, err := regexp.MatchString(pattern, output)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("invalid expect%s%sContains pattern %q: %w", strings.ToUpper(stream[:1]), stream[1:], pattern, err)
 	}
+	if !matched {
+		return fmt.Sprintf("%s did not match expected pattern %q", stream, pattern), nil
+	}
+	return "", nil
+}
 
-	if len(entries1) != len(entries2) {
-		return fmt.Errorf("directories %s and %s have different number of files", dir1, dir2)
+// updateGoldenFiles rewrites afterDir/metadata and afterDir/output from the
+// observed contents under tmpdir, the same way `go test -update` refreshes
+// golden files from a test's actual output.
+func updateGoldenFiles(tmpdir, afterDir string) error {
+	for _, dir := range []string{"metadata", "output"} {
+		dest := path.Join(afterDir, dir)
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+		if err := copyTree(path.Join(tmpdir, dir), dest); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	for i, entry1 := range entries1 {
-		entry2 := entries2[i]
+// copyTree recursively copies srcDir's contents into destDir. It copies
+// rather than renames because tmpdir is created under os.TempDir, which is
+// frequently a different filesystem to the repo (e.g. tmpfs vs. overlay in
+// CI); os.Rename across filesystems fails with EXDEV.
+func copyTree(srcDir, destDir string) error {
+	return filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 
-		if entry1.Name() != entry2.Name() {
-			return fmt.Errorf("files %s and %s are not the same", entry1.Name(), entry2.Name())
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
 		}
+		target := filepath.Join(destDir, rel)
 
-		if entry1.IsDir() {
-			err = compareDirs(path.Join(dir1, entry1.Name()), path.Join(dir2, entry2.Name()))
-			if err != nil {
-				return err
-			}
-		} else {
-			err = compareFiles(path.Join(dir1, entry1.Name()), path.Join(dir2, entry2.Name()))
-			if err != nil {
-				return err
-			}
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
 		}
-	}
 
-	return nil
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return copyFile(p, target, info.Mode())
+	})
 }
 
-func compareFiles(file1, file2 string) error {
-	contents1, err := os.ReadFile(file1)
+// copyFile copies src to dest, creating dest's parent directory and
+// preserving mode.
+func copyFile(src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
+	defer in.Close()
 
-	contents2, err := os.ReadFile(file2)
-	if err != nil {
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
 		return err
 	}
 
-	if string(contents1) != string(contents2) {
-		return fmt.Errorf("files %s and %s do not have the same contents", file1, file2)
-	}
-
-	return nil
-}
-
-func copyDir(src, dst string) error {
-	sourceDir, err := os.ReadDir(src)
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
 	if err != nil {
 		return err
 	}
+	defer out.Close()
 
-	for _, entry := range sourceDir {
-		sourcePath := path.Join(src, entry.Name())
-		destPath := path.Join(dst, entry.Name())
+	_, err = io.Copy(out, in)
+	return err
+}
 
-		if entry.IsDir() {
-			err = os.MkdirAll(destPath, os.ModePerm)
-			if err != nil {
-				return err
-			}
-			err = copyDir(sourcePath, destPath)
-			if err != nil {
-				return err
-			}
-		} else {
-			err = copyFile(sourcePath, destPath)
-			if err != nil {
-				return err
+// summarizeDiffs renders a map of per-subdirectory diffs as a short,
+// human-readable multi-line error for text-mode output. JSON-mode output
+// uses the structured Diffs field on testcaseResult instead.
+func summarizeDiffs(diffs map[string]*diff.DirDiff) string {
+	var lines []string
+	for _, dir := range []string{"metadata", "output"} {
+		dirDiff, ok := diffs[dir]
+		if !ok {
+			continue
+		}
+		for _, added := range dirDiff.Added {
+			lines = append(lines, fmt.Sprintf("%s/%s: unexpected file", dir, added))
+		}
+		for _, removed := range dirDiff.Removed {
+			lines = append(lines, fmt.Sprintf("%s/%s: missing file", dir, removed))
+		}
+		for _, fileDiff := range dirDiff.Files {
+			lines = append(lines, fmt.Sprintf("%s/%s:", dir, fileDiff.Path))
+			for _, change := range fileDiff.Changes {
+				lines = append(lines, "  "+change)
 			}
 		}
 	}
+	return strings.Join(lines, "\n")
+}
 
-	return nil
+// resolveKubeconfigPath returns the kubeconfig to stage into the container's
+// input, if any. An explicit --kubeconfig flag always wins; otherwise, when
+// a KinD cluster is in use, it defaults to the user's own kubeconfig so
+// existing `--kind-cluster` invocations keep working without change.
+func resolveKubeconfigPath() (string, error) {
+	if kubeconfigPath != "" {
+		return kubeconfigPath, nil
+	}
+	if kindCluster == "" {
+		return "", nil
+	}
+
+	homedir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(homedir, ".kube", "config"), nil
 }
 
 func buildAndLoadImage(containerArgs *ContainerCmdArgs, clusterName string) (string, error) {
@@ -323,15 +677,26 @@ func buildAndLoadImage(containerArgs *ContainerCmdArgs, clusterName string) (str
 
 	pipelineDir := path.Join("workflows", containerArgs.Lifecycle, containerArgs.Action, containerArgs.Pipeline)
 
-	printfVerbose("Building test image...")
-	if err := forkBuilderCommand(buildContainerOpts, imageName, pipelineDir, containerArgs.Container); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	builder, err := imagebuilder.ForName(builderName)
+	if err != nil {
+		return "", err
+	}
+
+	printfVerbose("Building test image with %q...", builderName)
+	buildOpts := imagebuilder.Options{
+		Registry:    registry,
+		KindCluster: clusterName,
+		BuildArgs:   buildArgs,
+		Platform:    platform,
+		Verbose:     verbose,
+	}
+	if err := builder.Build(pipelineDir, containerArgs.Container, imageName, buildOpts); err != nil {
+		return "", err
 	}
 
 	if clusterName != "" {
 		printfVerbose("Loading image into KinD cluster...")
-		if err := kindLoadImage(imageName, clusterName); err != nil {
+		if err := builder.LoadIntoKind(imageName, clusterName, verbose); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			return "", err
 		}
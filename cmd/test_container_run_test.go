@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/syntasso/kratix-cli/pkg/testcase"
+)
+
+// makeTestcaseDirs creates a testcase subdirectory for each entry in tags,
+// keyed by name, writing a testcase.yaml with the given tags when non-nil.
+func makeTestcaseDirs(t *testing.T, tags map[string][]string) string {
+	t.Helper()
+
+	imageDir := t.TempDir()
+	for name, testTags := range tags {
+		dir := filepath.Join(imageDir, name)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if testTags == nil {
+			continue
+		}
+		data, err := yaml.Marshal(testcase.Spec{Tags: testTags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, testcase.FileName), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return imageDir
+}
+
+func baseNames(dirs []string) []string {
+	var names []string
+	for _, dir := range dirs {
+		names = append(names, filepath.Base(dir))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestGetTestcaseDirsNoFilters(t *testing.T) {
+	imageDir := makeTestcaseDirs(t, map[string][]string{
+		"crud-create": nil,
+		"crud-delete": nil,
+		"failure-bad": nil,
+	})
+
+	dirs, err := getTestcaseDirs(imageDir, "", "", "")
+	if err != nil {
+		t.Fatalf("getTestcaseDirs: %v", err)
+	}
+
+	want := []string{"crud-create", "crud-delete", "failure-bad"}
+	if got := baseNames(dirs); !equalStrings(got, want) {
+		t.Errorf("getTestcaseDirs() = %v, want %v", got, want)
+	}
+}
+
+func TestGetTestcaseDirsGlob(t *testing.T) {
+	imageDir := makeTestcaseDirs(t, map[string][]string{
+		"crud-create": nil,
+		"crud-delete": nil,
+		"failure-bad": nil,
+	})
+
+	dirs, err := getTestcaseDirs(imageDir, "crud-*", "", "")
+	if err != nil {
+		t.Fatalf("getTestcaseDirs: %v", err)
+	}
+
+	want := []string{"crud-create", "crud-delete"}
+	if got := baseNames(dirs); !equalStrings(got, want) {
+		t.Errorf("getTestcaseDirs() = %v, want %v", got, want)
+	}
+}
+
+func TestGetTestcaseDirsMissingExactName(t *testing.T) {
+	imageDir := makeTestcaseDirs(t, map[string][]string{
+		"crud-create": nil,
+	})
+
+	if _, err := getTestcaseDirs(imageDir, "does-not-exist", "", ""); err == nil {
+		t.Fatal("getTestcaseDirs() = nil error, want one for a missing exact testcase name")
+	}
+}
+
+func TestGetTestcaseDirsTagsAndSkip(t *testing.T) {
+	imageDir := makeTestcaseDirs(t, map[string][]string{
+		"smoke-one": {"smoke"},
+		"smoke-two": {"smoke", "slow"},
+		"other":     {"integration"},
+	})
+
+	dirs, err := getTestcaseDirs(imageDir, "", "smoke", "*-two")
+	if err != nil {
+		t.Fatalf("getTestcaseDirs: %v", err)
+	}
+
+	want := []string{"smoke-one"}
+	if got := baseNames(dirs); !equalStrings(got, want) {
+		t.Errorf("getTestcaseDirs() = %v, want %v", got, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
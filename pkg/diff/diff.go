@@ -0,0 +1,384 @@
+// Package diff compares a testcase's observed output against its golden
+// after/ directory, producing a structured, machine-readable description of
+// any mismatch instead of the old "files differ" pass/fail signal.
+//
+// YAML and JSON files are compared semantically - key order and scalar
+// formatting don't matter, only the parsed value does. Everything else
+// falls back to a byte-level unified diff.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// DirDiff describes how two directory trees differ.
+type DirDiff struct {
+	// Added lists entries present in got but not in want.
+	Added []string `json:"added,omitempty"`
+	// Removed lists entries present in want but not in got.
+	Removed []string `json:"removed,omitempty"`
+	// Files holds a FileDiff for every file present in both trees whose
+	// contents differ.
+	Files []FileDiff `json:"files,omitempty"`
+}
+
+// Empty reports whether the directories were identical.
+func (d *DirDiff) Empty() bool {
+	return d != nil && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Files) == 0
+}
+
+// FileDiff describes a single differing file.
+type FileDiff struct {
+	Path string `json:"path"`
+	// Kind is "semantic" for YAML/JSON comparisons or "byte" for the
+	// unified-diff fallback.
+	Kind string `json:"kind"`
+	// Changes holds human-readable diff lines: semantic key changes for
+	// "semantic", or unified-diff hunks for "byte".
+	Changes []string `json:"changes"`
+}
+
+// CompareDirs walks gotDir and wantDir (the observed output and the golden
+// after/ directory, respectively) and reports every difference found,
+// rather than aborting on the first directory-shape mismatch. Paths
+// matching any of the skip glob patterns (matched against the path
+// relative to the two directories) are excluded from the comparison
+// entirely, per a testcase's skipCompare configuration.
+func CompareDirs(gotDir, wantDir string, skip []string) (*DirDiff, error) {
+	gotEntries, err := relFiles(gotDir)
+	if err != nil {
+		return nil, err
+	}
+	wantEntries, err := relFiles(wantDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for rel := range gotEntries {
+		if matchesAny(skip, rel) {
+			delete(gotEntries, rel)
+		}
+	}
+	for rel := range wantEntries {
+		if matchesAny(skip, rel) {
+			delete(wantEntries, rel)
+		}
+	}
+
+	result := &DirDiff{}
+
+	for rel := range gotEntries {
+		if _, ok := wantEntries[rel]; !ok {
+			result.Added = append(result.Added, rel)
+		}
+	}
+	for rel := range wantEntries {
+		if _, ok := gotEntries[rel]; !ok {
+			result.Removed = append(result.Removed, rel)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+
+	var common []string
+	for rel := range gotEntries {
+		if _, ok := wantEntries[rel]; ok {
+			common = append(common, rel)
+		}
+	}
+	sort.Strings(common)
+
+	for _, rel := range common {
+		fileDiff, err := CompareFiles(filepath.Join(gotDir, rel), filepath.Join(wantDir, rel))
+		if err != nil {
+			return nil, err
+		}
+		if fileDiff != nil {
+			fileDiff.Path = rel
+			result.Files = append(result.Files, *fileDiff)
+		}
+	}
+
+	return result, nil
+}
+
+// CompareFiles compares a single pair of files and returns a FileDiff if
+// they differ, or nil if they're equivalent. YAML (.yaml/.yml) and JSON
+// (.json) files are compared semantically; everything else is compared
+// byte-for-byte with a unified diff.
+func CompareFiles(gotPath, wantPath string) (*FileDiff, error) {
+	gotBytes, err := os.ReadFile(gotPath)
+	if err != nil {
+		return nil, err
+	}
+	wantBytes, err := os.ReadFile(wantPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(wantPath) {
+	case ".yaml", ".yml", ".json":
+		return semanticDiff(gotBytes, wantBytes)
+	default:
+		return byteDiff(gotPath, wantPath, gotBytes, wantBytes)
+	}
+}
+
+// semanticDiff decodes both sides as a sequence of YAML documents (a
+// superset of JSON, which is always a single document) and recursively
+// compares each pair, ignoring map key order and normalizing scalars so
+// e.g. `replicas: 3` and `replicas: 3.0` are equal. Kratix output is
+// routinely multi-document (`---`-separated resources), so every document
+// is compared, not just the first.
+func semanticDiff(gotBytes, wantBytes []byte) (*FileDiff, error) {
+	gotDocs, gotErr := decodeYAMLDocuments(gotBytes)
+	wantDocs, wantErr := decodeYAMLDocuments(wantBytes)
+	if gotErr != nil || wantErr != nil {
+		return byteDiffFromContents("semantic-parse-failed", gotBytes, wantBytes)
+	}
+
+	docCount := len(wantDocs)
+	if len(gotDocs) > docCount {
+		docCount = len(gotDocs)
+	}
+
+	var changes []string
+	for i := 0; i < docCount; i++ {
+		docPath := "$"
+		if docCount > 1 {
+			docPath = fmt.Sprintf("$[%d]", i)
+		}
+
+		switch {
+		case i >= len(gotDocs):
+			changes = append(changes, fmt.Sprintf("%s: removed document (was %v)", docPath, wantDocs[i]))
+		case i >= len(wantDocs):
+			changes = append(changes, fmt.Sprintf("%s: added document (now %v)", docPath, gotDocs[i]))
+		default:
+			walkDiff(docPath, normalize(wantDocs[i]), normalize(gotDocs[i]), &changes)
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	return &FileDiff{Kind: "semantic", Changes: changes}, nil
+}
+
+// decodeYAMLDocuments decodes every `---`-separated document in data.
+func decodeYAMLDocuments(data []byte) ([]any, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+
+	var docs []any
+	for {
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// normalize recursively converts map[string]interface{} keys and numeric
+// scalars into a canonical form so that equivalent YAML/JSON documents
+// compare equal regardless of key order or numeric representation.
+func normalize(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, v := range val {
+			out[k] = normalize(v)
+		}
+		return out
+	case map[any]any:
+		out := make(map[string]any, len(val))
+		for k, v := range val {
+			out[fmt.Sprintf("%v", k)] = normalize(v)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, v := range val {
+			out[i] = normalize(v)
+		}
+		return out
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	default:
+		return val
+	}
+}
+
+// walkDiff recursively compares want against got, appending a human
+// readable "path: want -> got" entry for every leaf that differs.
+func walkDiff(path string, want, got any, out *[]string) {
+	wantMap, wantIsMap := want.(map[string]any)
+	gotMap, gotIsMap := got.(map[string]any)
+	if wantIsMap && gotIsMap {
+		keys := make(map[string]bool)
+		for k := range wantMap {
+			keys[k] = true
+		}
+		for k := range gotMap {
+			keys[k] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+
+		for _, k := range sortedKeys {
+			wv, wok := wantMap[k]
+			gv, gok := gotMap[k]
+			childPath := fmt.Sprintf("%s.%s", path, k)
+			switch {
+			case !gok:
+				*out = append(*out, fmt.Sprintf("%s: removed (was %v)", childPath, wv))
+			case !wok:
+				*out = append(*out, fmt.Sprintf("%s: added (now %v)", childPath, gv))
+			default:
+				walkDiff(childPath, wv, gv, out)
+			}
+		}
+		return
+	}
+
+	wantList, wantIsList := want.([]any)
+	gotList, gotIsList := got.([]any)
+	if wantIsList && gotIsList {
+		maxLen := len(wantList)
+		if len(gotList) > maxLen {
+			maxLen = len(gotList)
+		}
+		for i := 0; i < maxLen; i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(gotList):
+				*out = append(*out, fmt.Sprintf("%s: removed (was %v)", childPath, wantList[i]))
+			case i >= len(wantList):
+				*out = append(*out, fmt.Sprintf("%s: added (now %v)", childPath, gotList[i]))
+			default:
+				walkDiff(childPath, wantList[i], gotList[i], out)
+			}
+		}
+		return
+	}
+
+	if !equalScalar(want, got) {
+		*out = append(*out, fmt.Sprintf("%s: %v -> %v", path, want, got))
+	}
+}
+
+func equalScalar(a, b any) bool {
+	aj, _ := json.Marshal(a)
+	bj, _ := json.Marshal(b)
+	return string(aj) == string(bj)
+}
+
+// byteDiff produces a unified diff with 3 lines of context, modeled on
+// `diff -u`, for files with no semantic structure.
+func byteDiff(gotPath, wantPath string, gotBytes, wantBytes []byte) (*FileDiff, error) {
+	fileDiff, err := byteDiffFromContents(wantPath, gotBytes, wantBytes)
+	if err != nil {
+		return nil, err
+	}
+	if fileDiff != nil {
+		fileDiff.Kind = "byte"
+	}
+	return fileDiff, nil
+}
+
+func byteDiffFromContents(label string, gotBytes, wantBytes []byte) (*FileDiff, error) {
+	if string(gotBytes) == string(wantBytes) {
+		return nil, nil
+	}
+
+	udiff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(wantBytes)),
+		B:        difflib.SplitLines(string(gotBytes)),
+		FromFile: "want/" + label,
+		ToFile:   "got/" + label,
+		Context:  3,
+	}
+
+	text, err := difflib.GetUnifiedDiffString(udiff)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileDiff{Kind: "byte", Changes: splitNonEmptyLines(text)}, nil
+}
+
+func splitNonEmptyLines(s string) []string {
+	var lines []string
+	start := 0
+	for i, r := range s {
+		if r == '\n' {
+			if line := s[start:i]; line != "" {
+				lines = append(lines, line)
+			}
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		lines = append(lines, s[start:])
+	}
+	return lines
+}
+
+// matchesAny reports whether rel matches any of the given glob patterns, as
+// per filepath.Match. A malformed pattern is treated as a non-match rather
+// than an error, since skipCompare patterns come from a testcase.yaml the
+// user may have gotten wrong.
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, err := filepath.Match(pattern, rel); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// relFiles returns the set of non-directory paths (regular files and
+// symlinks alike) under root, relative to root, using slash separators.
+func relFiles(root string) (map[string]bool, error) {
+	files := make(map[string]bool)
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	in := map[any]any{
+		"replicas": 3,
+		"nested":   map[any]any{"count": int64(4)},
+	}
+
+	got := normalize(in)
+
+	m, ok := got.(map[string]any)
+	if !ok {
+		t.Fatalf("normalize(%v) = %T, want map[string]any", in, got)
+	}
+	if m["replicas"] != float64(3) {
+		t.Errorf("normalize() replicas = %v (%T), want float64(3)", m["replicas"], m["replicas"])
+	}
+	nested, ok := m["nested"].(map[string]any)
+	if !ok {
+		t.Fatalf("normalize() nested = %T, want map[string]any", m["nested"])
+	}
+	if nested["count"] != float64(4) {
+		t.Errorf("normalize() nested.count = %v, want float64(4)", nested["count"])
+	}
+}
+
+func TestWalkDiff(t *testing.T) {
+	want := map[string]any{"a": float64(1), "b": map[string]any{"c": "x"}}
+	got := map[string]any{"a": float64(2), "b": map[string]any{"c": "x"}, "d": "new"}
+
+	var changes []string
+	walkDiff("$", want, got, &changes)
+
+	if len(changes) != 2 {
+		t.Fatalf("walkDiff produced %d changes, want 2: %v", len(changes), changes)
+	}
+}
+
+func TestSemanticDiffMultiDocument(t *testing.T) {
+	want := []byte("a: 1\n---\nb: 2\n")
+	got := []byte("a: 1\n---\nb: 3\n")
+
+	fileDiff, err := semanticDiff(got, want)
+	if err != nil {
+		t.Fatalf("semanticDiff: %v", err)
+	}
+	if fileDiff == nil {
+		t.Fatal("semanticDiff returned nil, want a diff for the second document")
+	}
+	if len(fileDiff.Changes) != 1 || fileDiff.Changes[0] != "$[1].b: 2 -> 3" {
+		t.Errorf("semanticDiff changes = %v, want [\"$[1].b: 2 -> 3\"]", fileDiff.Changes)
+	}
+}
+
+func TestSemanticDiffIgnoresKeyOrderAndNumericFormatting(t *testing.T) {
+	want := []byte("a: 1\nb: 2.0\n")
+	got := []byte("b: 2\na: 1.0\n")
+
+	fileDiff, err := semanticDiff(got, want)
+	if err != nil {
+		t.Fatalf("semanticDiff: %v", err)
+	}
+	if fileDiff != nil {
+		t.Errorf("semanticDiff = %v, want nil for equivalent documents", fileDiff)
+	}
+}
+
+func TestCompareDirsSkip(t *testing.T) {
+	gotDir, wantDir := t.TempDir(), t.TempDir()
+
+	writeFile(t, filepath.Join(gotDir, "keep.txt"), "same")
+	writeFile(t, filepath.Join(wantDir, "keep.txt"), "same")
+	writeFile(t, filepath.Join(gotDir, "timestamps.log"), "2026-07-29")
+	writeFile(t, filepath.Join(wantDir, "timestamps.log"), "2026-01-01")
+
+	dirDiff, err := CompareDirs(gotDir, wantDir, []string{"timestamps.log"})
+	if err != nil {
+		t.Fatalf("CompareDirs: %v", err)
+	}
+	if !dirDiff.Empty() {
+		t.Errorf("CompareDirs() = %+v, want empty with timestamps.log skipped", dirDiff)
+	}
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package imagebuilder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+)
+
+// buildahBuilder builds an image with `buildah bud`, leaving it in
+// buildah's local container storage.
+type buildahBuilder struct{}
+
+func (b *buildahBuilder) Build(pipelineDir, containerName, imageName string, opts Options) error {
+	contextDir := path.Join(pipelineDir, containerName)
+
+	args := append([]string{"bud", "-t", imageName}, commonBuildArgs(opts)...)
+	args = append(args, contextDir)
+
+	cmd := exec.Command("buildah", args...)
+	if opts.Verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("buildah bud: %w", err)
+	}
+
+	return nil
+}
+
+// LoadIntoKind loads imageName into clusterName. buildah's build output
+// lives in its own local container storage, not the Docker daemon, so
+// `kind load docker-image` can't see it; instead, push it to a
+// docker-archive tar with `buildah push` and load that archive.
+func (b *buildahBuilder) LoadIntoKind(imageName, clusterName string, verbose bool) error {
+	return kindLoadImageArchive(imageName, clusterName, verbose, func(archivePath string) error {
+		cmd := exec.Command("buildah", "push", imageName, fmt.Sprintf("docker-archive:%s:%s", archivePath, imageName))
+		return runMaybeVerbose(cmd, verbose)
+	})
+}
@@ -0,0 +1,74 @@
+// Package imagebuilder builds Kratix pipeline container images through a
+// pluggable backend, so `kratix test container run` isn't limited to
+// machines with a local Docker daemon. docker, podman, and buildah all
+// build locally; kaniko instead builds in-cluster, for machines without a
+// local daemon at all (Apple silicon CI runners, locked-down corporate
+// laptops) - matching how Kratix pipelines are actually built in
+// production clusters.
+package imagebuilder
+
+import "fmt"
+
+// Options configures how a Builder builds and makes an image available.
+type Options struct {
+	// Registry is the host:port of an image registry to push to. Required
+	// by the kaniko backend, which builds in-cluster and leaves no local
+	// image to load into KinD.
+	Registry string
+	// KindCluster is the name of the KinD cluster to load the built image
+	// into (docker/podman/buildah), if any. kaniko requires it to be set but
+	// does not use the name: its kubectl calls run against the current
+	// kubeconfig context.
+	KindCluster string
+	// BuildArgs is passed to the build as `--build-arg KEY=VALUE` for each
+	// entry, on every backend.
+	BuildArgs []string
+	// Platform, if set, is passed to the build as `--platform value` (or,
+	// for kaniko, `--custom-platform`).
+	Platform string
+	Verbose  bool
+}
+
+// Builder builds the Dockerfile under pipelineDir/containerName and makes
+// it available as imageName: loaded into the local Docker daemon for
+// docker/podman/buildah, or pushed to opts.Registry for kaniko.
+type Builder interface {
+	Build(pipelineDir, containerName, imageName string, opts Options) error
+
+	// LoadIntoKind makes an image already produced by Build available to
+	// the named KinD cluster. Backends differ in how imageName ends up
+	// stored after Build, so each one loads it into KinD differently (or,
+	// for kaniko, not at all - it pushes straight to a registry).
+	LoadIntoKind(imageName, clusterName string, verbose bool) error
+}
+
+// commonBuildArgs renders opts.BuildArgs/opts.Platform as the
+// `--build-arg`/`--platform` flags shared by docker, podman, and buildah's
+// `build`/`bud` dialects.
+func commonBuildArgs(opts Options) []string {
+	var args []string
+	for _, buildArg := range opts.BuildArgs {
+		args = append(args, "--build-arg", buildArg)
+	}
+	if opts.Platform != "" {
+		args = append(args, "--platform", opts.Platform)
+	}
+	return args
+}
+
+// ForName returns the Builder for the named backend. An empty name
+// defaults to "docker", matching the tool's pre-existing behavior.
+func ForName(name string) (Builder, error) {
+	switch name {
+	case "", "docker":
+		return &localCLIBuilder{binary: "docker"}, nil
+	case "podman":
+		return &localCLIBuilder{binary: "podman"}, nil
+	case "buildah":
+		return &buildahBuilder{}, nil
+	case "kaniko":
+		return &kanikoBuilder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown image builder %q, expected docker, podman, buildah, or kaniko", name)
+	}
+}
@@ -0,0 +1,182 @@
+package imagebuilder
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// kanikoBuilder builds an image in-cluster by running the kaniko executor
+// as a Job, for machines with no local Docker daemon at all. Every kubectl
+// call runs against the current kubeconfig context; Options.KindCluster is
+// checked to be set but is not used to pick the cluster. The pipeline's build context is staged into the job's pod
+// via an init container that blocks until `kubectl cp` has populated a
+// shared emptyDir, kaniko then builds and pushes to opts.Registry - there
+// is no local image to `kind load docker-image`, since the daemon was
+// never involved.
+type kanikoBuilder struct{}
+
+var kanikoJobTemplate = template.Must(template.New("kaniko-job").Parse(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{.JobName}}
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      restartPolicy: Never
+      initContainers:
+        - name: wait-for-context
+          image: busybox
+          command: ["sh", "-c", "until [ -f /workspace/.ready ]; do sleep 1; done"]
+          volumeMounts:
+            - name: context
+              mountPath: /workspace
+      containers:
+        - name: kaniko
+          image: gcr.io/kaniko-project/executor:latest
+          args:
+            - --dockerfile=/workspace/Dockerfile
+            - --context=dir:///workspace
+            - --destination={{.Destination}}
+            - --insecure
+            - --skip-tls-verify
+{{- range .BuildArgs}}
+            - --build-arg={{.}}
+{{- end}}
+{{- if .Platform}}
+            - --custom-platform={{.Platform}}
+{{- end}}
+          volumeMounts:
+            - name: context
+              mountPath: /workspace
+      volumes:
+        - name: context
+          emptyDir: {}
+`))
+
+func (b *kanikoBuilder) Build(pipelineDir, containerName, imageName string, opts Options) error {
+	if opts.Registry == "" {
+		return fmt.Errorf("--registry is required when using --builder=kaniko")
+	}
+	if opts.KindCluster == "" {
+		return fmt.Errorf("--kind-cluster is required when using --builder=kaniko")
+	}
+
+	jobName := fmt.Sprintf("kratix-kaniko-%d", time.Now().UnixNano())
+	destination := fmt.Sprintf("%s/%s", opts.Registry, imageName)
+
+	manifest, err := renderKanikoJob(jobName, destination, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := kubectlApply(manifest); err != nil {
+		return fmt.Errorf("creating kaniko job: %w", err)
+	}
+	defer kubectlDeleteJob(jobName)
+
+	podName, err := waitForPodScheduled(jobName)
+	if err != nil {
+		return fmt.Errorf("waiting for kaniko pod: %w", err)
+	}
+
+	contextDir := path.Join(pipelineDir, containerName)
+	if err := stageBuildContext(podName, contextDir); err != nil {
+		return fmt.Errorf("staging build context: %w", err)
+	}
+
+	if err := waitForJobComplete(jobName, opts.Verbose); err != nil {
+		return fmt.Errorf("kaniko build failed: %w", err)
+	}
+
+	return nil
+}
+
+// LoadIntoKind is a no-op: kaniko pushes straight to Options.Registry from
+// inside the cluster during Build, so there's no local image left for KinD
+// to load.
+func (b *kanikoBuilder) LoadIntoKind(imageName, clusterName string, verbose bool) error {
+	return nil
+}
+
+func renderKanikoJob(jobName, destination string, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	err := kanikoJobTemplate.Execute(&buf, struct {
+		JobName     string
+		Destination string
+		BuildArgs   []string
+		Platform    string
+	}{jobName, destination, opts.BuildArgs, opts.Platform})
+	return buf.Bytes(), err
+}
+
+func kubectlApply(manifest []byte) error {
+	cmd := exec.Command("kubectl", "apply", "-f", "-")
+	cmd.Stdin = bytes.NewReader(manifest)
+	return cmd.Run()
+}
+
+func kubectlDeleteJob(jobName string) {
+	_ = exec.Command("kubectl", "delete", "job", jobName, "--ignore-not-found", "--wait=false").Run()
+}
+
+// waitForPodScheduled waits for the Job's pod to exist and its
+// wait-for-context init container to be running, then returns the pod's
+// name so the build context can be staged into it.
+//
+// The pod's Initialized condition can't be used here: it only becomes true
+// once every init container has exited, but wait-for-context blocks until
+// stageBuildContext writes /workspace/.ready, which happens after this
+// function returns. Waiting on Initialized would therefore deadlock every
+// build. Instead, poll until the init container itself reports running.
+func waitForPodScheduled(jobName string) (string, error) {
+	selector := fmt.Sprintf("job-name=%s", jobName)
+	deadline := time.Now().Add(120 * time.Second)
+
+	for {
+		out, err := exec.Command("kubectl", "get", "pod", "--selector="+selector, "-o",
+			`jsonpath={.items[0].metadata.name}{"\t"}{.items[0].status.initContainerStatuses[0].state.running}`).Output()
+		if err == nil {
+			fields := strings.SplitN(strings.TrimSpace(string(out)), "\t", 2)
+			podName := fields[0]
+			if podName != "" && len(fields) > 1 && fields[1] != "" {
+				return podName, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("timed out waiting for kaniko pod's wait-for-context init container to start running")
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// stageBuildContext copies contextDir into the pod's shared /workspace
+// volume, then drops a /workspace/.ready marker so the init container lets
+// kaniko start.
+func stageBuildContext(podName, contextDir string) error {
+	dest := fmt.Sprintf("%s:/workspace", podName)
+	if err := exec.Command("kubectl", "cp", contextDir+"/.", dest, "-c", "wait-for-context").Run(); err != nil {
+		return err
+	}
+
+	return exec.Command("kubectl", "exec", podName, "-c", "wait-for-context", "--",
+		"touch", "/workspace/.ready").Run()
+}
+
+func waitForJobComplete(jobName string, verbose bool) error {
+	if verbose {
+		logs := exec.Command("kubectl", "logs", "-f", "job/"+jobName, "-c", "kaniko")
+		logs.Stdout = os.Stdout
+		logs.Stderr = os.Stderr
+		_ = logs.Run()
+	}
+
+	return exec.Command("kubectl", "wait", "job/"+jobName, "--for=condition=complete", "--timeout=600s").Run()
+}
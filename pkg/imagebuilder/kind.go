@@ -0,0 +1,44 @@
+package imagebuilder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// kindLoadDockerImage loads an image already present in the local Docker
+// daemon into clusterName, via `kind load docker-image`.
+func kindLoadDockerImage(imageName, clusterName string, verbose bool) error {
+	cmd := exec.Command("kind", "load", "docker-image", imageName, "--name", clusterName)
+	return runMaybeVerbose(cmd, verbose)
+}
+
+// kindLoadImageArchive exports imageName to a temporary tar archive using
+// export (a backend-specific callback that writes the archive to the given
+// path), then loads that archive into clusterName via
+// `kind load image-archive`. Used by backends whose built image lives
+// outside the Docker daemon, where `kind load docker-image` can't find it.
+func kindLoadImageArchive(imageName, clusterName string, verbose bool, export func(archivePath string) error) error {
+	f, err := os.CreateTemp("", "kratix-kind-image-*.tar")
+	if err != nil {
+		return err
+	}
+	archivePath := f.Name()
+	f.Close()
+	defer os.Remove(archivePath)
+
+	if err := export(archivePath); err != nil {
+		return fmt.Errorf("exporting %s for KinD: %w", imageName, err)
+	}
+
+	cmd := exec.Command("kind", "load", "image-archive", archivePath, "--name", clusterName)
+	return runMaybeVerbose(cmd, verbose)
+}
+
+func runMaybeVerbose(cmd *exec.Cmd, verbose bool) error {
+	if verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	return cmd.Run()
+}
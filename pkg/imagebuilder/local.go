@@ -0,0 +1,49 @@
+package imagebuilder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+)
+
+// localCLIBuilder builds an image with a local CLI binary that speaks the
+// `docker build` dialect - used for both docker and podman, since podman's
+// build subcommand is a drop-in replacement.
+type localCLIBuilder struct {
+	binary string
+}
+
+func (b *localCLIBuilder) Build(pipelineDir, containerName, imageName string, opts Options) error {
+	contextDir := path.Join(pipelineDir, containerName)
+
+	args := append([]string{"build", "-t", imageName}, commonBuildArgs(opts)...)
+	args = append(args, contextDir)
+
+	cmd := exec.Command(b.binary, args...)
+	if opts.Verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s build: %w", b.binary, err)
+	}
+
+	return nil
+}
+
+// LoadIntoKind loads imageName into clusterName. docker builds land
+// straight in the Docker daemon, so `kind load docker-image` can pick it
+// up directly; podman builds land in podman's own local storage instead,
+// which `kind load docker-image` can't see, so that image is exported to a
+// tar with `podman save` and loaded as an archive.
+func (b *localCLIBuilder) LoadIntoKind(imageName, clusterName string, verbose bool) error {
+	if b.binary == "docker" {
+		return kindLoadDockerImage(imageName, clusterName, verbose)
+	}
+
+	return kindLoadImageArchive(imageName, clusterName, verbose, func(archivePath string) error {
+		cmd := exec.Command(b.binary, "save", "-o", archivePath, imageName)
+		return runMaybeVerbose(cmd, verbose)
+	})
+}
@@ -0,0 +1,108 @@
+// Package testcase loads a testcase's optional testcase.yaml, which
+// configures how its container is invoked beyond the plain before/after
+// directory pair: environment variables, an overridden entrypoint, an
+// inline or file-referenced Kratix request object, a timeout, and pass/fail
+// expectations beyond a straight directory comparison.
+package testcase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the optional per-testcase spec file.
+const FileName = "testcase.yaml"
+
+// KratixRequest configures the Kratix request object staged into the
+// container's input as /kratix/input/object.yaml. Exactly one of Inline or
+// File should be set; if both are empty, no object.yaml is written beyond
+// whatever before/ already contains.
+type KratixRequest struct {
+	// Inline is a request object defined directly in testcase.yaml.
+	Inline map[string]any `yaml:"inline,omitempty"`
+	// File is a path, relative to the testcase directory, to a file whose
+	// contents are staged as the request object.
+	File string `yaml:"file,omitempty"`
+}
+
+// Spec is the parsed contents of a testcase.yaml file.
+type Spec struct {
+	// Env is passed to the container as `-e KEY=VALUE` for each entry.
+	Env map[string]string `yaml:"env,omitempty"`
+	// Command overrides the image's ENTRYPOINT.
+	Command string `yaml:"command,omitempty"`
+	// Args overrides the image's CMD.
+	Args []string `yaml:"args,omitempty"`
+	// KratixRequest, if set, is staged into the container's input as
+	// /kratix/input/object.yaml.
+	KratixRequest *KratixRequest `yaml:"kratixRequest,omitempty"`
+	// Timeout bounds how long the container is allowed to run, e.g. "30s".
+	// Empty means no timeout.
+	Timeout string `yaml:"timeout,omitempty"`
+	// ExpectExitCode is the exit code the container is expected to exit
+	// with; defaults to 0.
+	ExpectExitCode int `yaml:"expectExitCode,omitempty"`
+	// ExpectStdoutContains, if set, must match somewhere in the
+	// container's stdout, as a regular expression.
+	ExpectStdoutContains string `yaml:"expectStdoutContains,omitempty"`
+	// ExpectStderrContains, if set, must match somewhere in the
+	// container's stderr, as a regular expression.
+	ExpectStderrContains string `yaml:"expectStderrContains,omitempty"`
+	// SkipCompare lists glob patterns, matched against paths relative to
+	// metadata/ or output/, excluded from the after/ comparison.
+	SkipCompare []string `yaml:"skipCompare,omitempty"`
+	// Tags labels this testcase for selection via --tags, e.g.
+	// ["smoke", "slow", "integration"]. --skip matches testcase names, not
+	// tags.
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// Load reads testcaseDir/testcase.yaml, if present, returning an empty Spec
+// (not an error) when the file doesn't exist.
+func Load(testcaseDir string) (*Spec, error) {
+	data, err := os.ReadFile(filepath.Join(testcaseDir, FileName))
+	if os.IsNotExist(err) {
+		return &Spec{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", FileName, err)
+	}
+
+	return &spec, nil
+}
+
+// TimeoutDuration parses Timeout, returning 0 if it's unset.
+func (s *Spec) TimeoutDuration() (time.Duration, error) {
+	if s.Timeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s.Timeout)
+}
+
+// RequestObject resolves KratixRequest to the raw bytes that should be
+// staged as /kratix/input/object.yaml, or nil if no request is configured.
+// File paths are resolved relative to testcaseDir.
+func (s *Spec) RequestObject(testcaseDir string) ([]byte, error) {
+	if s.KratixRequest == nil {
+		return nil, nil
+	}
+
+	if s.KratixRequest.File != "" {
+		return os.ReadFile(filepath.Join(testcaseDir, s.KratixRequest.File))
+	}
+
+	if s.KratixRequest.Inline != nil {
+		return yaml.Marshal(s.KratixRequest.Inline)
+	}
+
+	return nil, nil
+}
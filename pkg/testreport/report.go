@@ -0,0 +1,140 @@
+// Package testreport renders testcase results as JUnit XML or JSON so that
+// `kratix test container run` can plug into CI test summaries (Jenkins,
+// GitHub Actions), the same way `go test -json` does for Go test output.
+package testreport
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CaseResult is the outcome of a single testcase, independent of how it is
+// rendered.
+type CaseResult struct {
+	Name     string
+	Passed   bool
+	Error    string
+	Duration time.Duration
+}
+
+// Report is an ordered collection of testcase results for a single
+// `kratix test container run` invocation.
+type Report struct {
+	SuiteName string
+	Cases     []CaseResult
+}
+
+// Failures returns the number of failed cases in the report.
+func (r *Report) Failures() int {
+	count := 0
+	for _, c := range r.Cases {
+		if !c.Passed {
+			count++
+		}
+	}
+	return count
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Time    float64       `xml:"time,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit renders the report as JUnit XML, modeled on the format
+// `go test -junit` style tooling and CI test-summary plugins expect.
+func (r *Report) WriteJUnit(path string) error {
+	suite := junitTestSuite{
+		Name:     r.SuiteName,
+		Tests:    len(r.Cases),
+		Failures: r.Failures(),
+	}
+
+	for _, c := range r.Cases {
+		tc := junitTestCase{
+			Name: c.Name,
+			Time: c.Duration.Seconds(),
+		}
+		if !c.Passed {
+			tc.Failure = &junitFailure{Message: "testcase failed", Text: c.Error}
+		}
+		suite.Time += tc.Time
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	out, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), out...), 0o644)
+}
+
+// jsonReport is the on-disk shape for WriteJSON, modeled loosely on `go test
+// -json`'s one-event-per-test-case structure.
+type jsonReport struct {
+	SuiteName string          `json:"suiteName"`
+	Tests     int             `json:"tests"`
+	Failures  int             `json:"failures"`
+	Cases     []jsonCaseEntry `json:"cases"`
+}
+
+type jsonCaseEntry struct {
+	Name       string  `json:"name"`
+	Passed     bool    `json:"passed"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// WriteJSON renders the report as JSON for consumption by scripts or CI
+// systems that don't speak JUnit.
+func (r *Report) WriteJSON(path string) error {
+	out := jsonReport{
+		SuiteName: r.SuiteName,
+		Tests:     len(r.Cases),
+		Failures:  r.Failures(),
+	}
+	for _, c := range r.Cases {
+		out.Cases = append(out.Cases, jsonCaseEntry{
+			Name:       c.Name,
+			Passed:     c.Passed,
+			Error:      c.Error,
+			DurationMs: float64(c.Duration.Microseconds()) / 1000,
+		})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ParseSpec splits a `--report` flag value of the form "format=path" (e.g.
+// "junit=report.xml") into its format and destination path.
+func ParseSpec(spec string) (format, path string, err error) {
+	for i := 0; i < len(spec); i++ {
+		if spec[i] == '=' {
+			return spec[:i], spec[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("invalid --report value %q, expected format=path (e.g. junit=report.xml)", spec)
+}
@@ -0,0 +1,49 @@
+package testreport
+
+import "testing"
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantFormat string
+		wantPath   string
+		wantErr    bool
+	}{
+		{spec: "junit=report.xml", wantFormat: "junit", wantPath: "report.xml"},
+		{spec: "json=out/report.json", wantFormat: "json", wantPath: "out/report.json"},
+		{spec: "junit=path/with=equals.xml", wantFormat: "junit", wantPath: "path/with=equals.xml"},
+		{spec: "no-equals-sign", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			format, path, err := ParseSpec(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSpec(%q) = (%q, %q), want error", tt.spec, format, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSpec(%q) returned unexpected error: %v", tt.spec, err)
+			}
+			if format != tt.wantFormat || path != tt.wantPath {
+				t.Errorf("ParseSpec(%q) = (%q, %q), want (%q, %q)", tt.spec, format, path, tt.wantFormat, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestReportFailures(t *testing.T) {
+	report := &Report{
+		Cases: []CaseResult{
+			{Name: "a", Passed: true},
+			{Name: "b", Passed: false},
+			{Name: "c", Passed: false},
+		},
+	}
+
+	if got := report.Failures(); got != 2 {
+		t.Errorf("Failures() = %d, want 2", got)
+	}
+}
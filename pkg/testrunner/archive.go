@@ -0,0 +1,225 @@
+package testrunner
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// buildTar walks srcDir and writes its contents as a tar stream to w. Paths
+// matched by ignore are skipped. Entries are written with paths relative to
+// srcDir, rooted under prefix, so that once extracted into the directory
+// prefix is itself relative to, srcDir's contents land under
+// <that directory>/prefix. extraFiles and extraContents, if given, are
+// added to the archive the same way - extraFiles from a file already on
+// the host (e.g. staging a kubeconfig), extraContents from in-memory data
+// with no host file (e.g. an inline Kratix request object).
+func buildTar(w io.Writer, srcDir string, ignore *Ignorer, extraFiles map[string]string, extraContents map[string][]byte, prefix string) error {
+	tw := tar.NewWriter(w)
+
+	if err := addDirToTar(tw, srcDir, ignore, prefix); err != nil {
+		return err
+	}
+
+	for destName, hostPath := range extraFiles {
+		if err := addFileToTar(tw, hostPath, path.Join(prefix, destName)); err != nil {
+			return err
+		}
+	}
+
+	for destName, data := range extraContents {
+		if err := addBytesToTar(tw, path.Join(prefix, destName), data); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// addDirToTar walks srcDir, writing each entry not matched by ignore into tw
+// with a path relative to srcDir, rooted under prefix.
+func addDirToTar(tw *tar.Writer, srcDir string, ignore *Ignorer, prefix string) error {
+	return filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == srcDir {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		if ignore.Match(relPath) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(relPath))
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			if _, err := io.Copy(tw, f); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// addFileToTar adds the file at hostPath to tw under destName.
+func addFileToTar(tw *tar.Writer, hostPath, destName string) error {
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = destName
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// addBytesToTar adds in-memory data to tw under destName.
+func addBytesToTar(tw *tar.Writer, destName string, data []byte) error {
+	header := &tar.Header{
+		Name: destName,
+		Mode: 0o644,
+		Size: int64(len(data)),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// extractTar extracts a tar stream read from r into destRoot. It is
+// symlink-safe: any entry whose name is absolute or whose ".." segments
+// would take it outside destRoot, and any symlink whose target, taken
+// relative to destRoot, would escape it, is rejected rather than silently
+// skipped, matching the defensive extraction podman/buildah use when
+// streaming archives out of containers.
+func extractTar(r io.Reader, destRoot string) error {
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(destRoot, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if _, err := safeJoin(destRoot, header.Linkname); err != nil {
+				return fmt.Errorf("tar entry %q: symlink target escapes extraction root: %w", header.Name, err)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			// Skip device files, FIFOs, etc. - not relevant to /kratix/output
+			// or /kratix/metadata contents.
+			continue
+		}
+
+		// Chowning requires privileges the test runner may not have (e.g.
+		// running as a non-root user); best-effort only.
+		_ = os.Chown(target, header.Uid, header.Gid)
+	}
+}
+
+// safeJoin joins name onto root and verifies the result stays within root,
+// rejecting absolute paths and ".." segments that would otherwise allow a
+// tar entry to write outside the extraction directory.
+func safeJoin(root, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("tar entry has absolute path %q", name)
+	}
+
+	cleaned := filepath.Clean(filepath.Join(root, name))
+	rootWithSep := filepath.Clean(root) + string(os.PathSeparator)
+	if cleaned != filepath.Clean(root) && !strings.HasPrefix(cleaned, rootWithSep) {
+		return "", fmt.Errorf("tar entry %q escapes extraction root %q", name, root)
+	}
+
+	return cleaned, nil
+}
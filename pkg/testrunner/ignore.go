@@ -0,0 +1,67 @@
+package testrunner
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+)
+
+// kratixIgnoreFile is the name of the optional per-testcase file listing
+// glob patterns to exclude when staging a testcase's before/ directory into
+// a container, analogous to a .dockerignore file.
+const kratixIgnoreFile = ".kratixignore"
+
+// Ignorer matches relative paths against a list of glob patterns loaded from
+// a .kratixignore file.
+type Ignorer struct {
+	patterns []string
+}
+
+// LoadIgnorer reads dir/.kratixignore, if present, and returns an Ignorer
+// for the glob patterns it contains. A missing file is not an error; it
+// simply results in an Ignorer that matches nothing.
+func LoadIgnorer(dir string) (*Ignorer, error) {
+	f, err := os.Open(filepath.Join(dir, kratixIgnoreFile))
+	if os.IsNotExist(err) {
+		return &Ignorer{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Ignorer{patterns: patterns}, nil
+}
+
+// Match reports whether relPath (slash-separated, relative to the directory
+// being archived), or its base name, matches any of the loaded glob
+// patterns.
+func (i *Ignorer) Match(relPath string) bool {
+	if i == nil {
+		return false
+	}
+
+	for _, pattern := range i.patterns {
+		if ok, _ := filepath.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(relPath)); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,284 @@
+// Package testrunner stages a Kratix pipeline container's input and
+// retrieves its output using `docker cp` tar streaming rather than bind
+// mounts. Bind mounts break under Docker Desktop's and rootless Docker's
+// permission/SELinux models and only work against a local daemon; streaming
+// tar archives in and out of the container over the Docker API works the
+// same way against a remote daemon and only requires swapping the CLI
+// binary to run under podman instead.
+package testrunner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	inputPath    = "/kratix/input"
+	outputPath   = "/kratix/output"
+	metadataPath = "/kratix/metadata"
+
+	// inputPrefix is inputPath with its leading slash stripped, used as the
+	// tar entry prefix when staging input: copying a tar stream into a
+	// container only requires the copy-in destination itself
+	// ("/", below) to already exist, and tar extraction creates any
+	// intermediate directories an entry's path needs, so this works even
+	// against an image that never created /kratix/input.
+	inputPrefix = "kratix/input"
+
+	// kubeconfigInputFile is where a staged kubeconfig is written inside
+	// the input tar when Params.KubeconfigPath is set, replacing the old
+	// ~/.kube/config bind mount.
+	kubeconfigInputFile = "kubeconfig"
+	// requestObjectInputFile is where a testcase's configured Kratix
+	// request object is written inside the input tar.
+	requestObjectInputFile = "object.yaml"
+)
+
+// Params configures a single Run.
+type Params struct {
+	Image  string
+	Before string // host directory streamed in as /kratix/input
+	Dest   string // host directory output/metadata are streamed out into
+
+	// KubeconfigPath, if set, is staged into the input tar as
+	// /kratix/input/kubeconfig instead of being bind-mounted from the host,
+	// and KUBECONFIG is set in the container's environment to point at it.
+	KubeconfigPath string
+	// ContainerName, if set, names the container (e.g. so concurrent
+	// testcase runs can be told apart with `docker ps`); if empty, Docker
+	// assigns a random name as usual.
+	ContainerName string
+
+	// Env is passed to the container as `-e KEY=VALUE` for each entry.
+	Env map[string]string
+	// Command, if set, overrides the image's ENTRYPOINT.
+	Command string
+	// Args, if set, overrides the image's CMD.
+	Args []string
+	// RequestObject, if non-nil, is staged into the input tar as
+	// /kratix/input/object.yaml.
+	RequestObject []byte
+	// Timeout bounds how long the container is allowed to run. Zero means
+	// no timeout.
+	Timeout time.Duration
+
+	Stdout, Stderr io.Writer
+}
+
+// Result is the outcome of running a single testcase container.
+type Result struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// Run stages params.Before into a new container's /kratix/input, runs the
+// container, and streams /kratix/output and /kratix/metadata back out into
+// params.Dest/output and params.Dest/metadata.
+//
+// params.Dest is created if it does not already exist. Run does not compare
+// the extracted output against any expected contents, nor does it check
+// the exit code or stdout/stderr against any expectation; that is the
+// caller's responsibility.
+func Run(params Params) (Result, error) {
+	containerID, err := createContainer(params)
+	if err != nil {
+		return Result{}, fmt.Errorf("creating container: %w", err)
+	}
+	defer removeContainer(containerID)
+
+	ignore, err := LoadIgnorer(params.Before)
+	if err != nil {
+		return Result{}, fmt.Errorf("loading .kratixignore: %w", err)
+	}
+
+	extraFiles := map[string]string{}
+	if params.KubeconfigPath != "" {
+		extraFiles[kubeconfigInputFile] = params.KubeconfigPath
+	}
+
+	extraContents := map[string][]byte{}
+	if params.RequestObject != nil {
+		extraContents[requestObjectInputFile] = params.RequestObject
+	}
+
+	var inputTar bytes.Buffer
+	if err := buildTar(&inputTar, params.Before, ignore, extraFiles, extraContents, inputPrefix); err != nil {
+		return Result{}, fmt.Errorf("building input tar: %w", err)
+	}
+
+	// The tar entries are already rooted at inputPrefix ("kratix/input/..."),
+	// so copy into "/" - which is guaranteed to exist - rather than
+	// inputPath itself, which may not.
+	if err := copyIn(containerID, &inputTar, "/"); err != nil {
+		return Result{}, fmt.Errorf("copying input into container: %w", err)
+	}
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if params.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+
+	result, err := startAndWait(ctx, containerID, params.Stdout, params.Stderr)
+	if err != nil {
+		return Result{}, fmt.Errorf("running container: %w", err)
+	}
+
+	for _, d := range []struct{ containerPath, hostSubdir string }{
+		{outputPath, "output"},
+		{metadataPath, "metadata"},
+	} {
+		destSubdir := filepath.Join(params.Dest, d.hostSubdir)
+		if err := os.MkdirAll(destSubdir, os.ModePerm); err != nil {
+			return Result{}, err
+		}
+		// `docker cp CONTAINER:d.containerPath -` yields a tar whose entries
+		// are already rooted at the source path's leaf directory name (e.g.
+		// "output/...", not just "..."), so extract into params.Dest, not
+		// destSubdir, or the result lands one level too deep.
+		if err := copyOut(containerID, d.containerPath, params.Dest); err != nil {
+			return Result{}, fmt.Errorf("copying %s out of container: %w", d.containerPath, err)
+		}
+	}
+
+	return result, nil
+}
+
+// createContainer creates (but does not start) a container from
+// params.Image, returning its ID. The container is created without volumes
+// so that all input is staged afterwards via copyIn.
+func createContainer(params Params) (string, error) {
+	args := []string{"create"}
+	if params.ContainerName != "" {
+		args = append(args, "--name", params.ContainerName)
+	}
+	for k, v := range params.Env {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	if params.KubeconfigPath != "" {
+		args = append(args, "-e", fmt.Sprintf("KUBECONFIG=%s/%s", inputPath, kubeconfigInputFile))
+	}
+	if params.Command != "" {
+		args = append(args, "--entrypoint", params.Command)
+	}
+	args = append(args, params.Image)
+	args = append(args, params.Args...)
+
+	cmd := exec.Command("docker", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// copyIn streams tarData into the container at destPath, equivalent to
+// `docker cp - CONTAINER:destPath`. Callers pass "/" and root the tar
+// entries themselves (see inputPrefix): copying a tar stream into a
+// container does NOT create destPath if it doesn't already exist - only
+// the intermediate directories implied by the tar entries' own paths are
+// created - so destPath itself must already exist in the image.
+func copyIn(containerID string, tarData *bytes.Buffer, destPath string) error {
+	cmd := exec.Command("docker", "cp", "-", fmt.Sprintf("%s:%s", containerID, destPath))
+	cmd.Stdin = tarData
+	return cmd.Run()
+}
+
+// copyOut streams srcPath out of the container and extracts it into
+// destDir, equivalent to `docker cp CONTAINER:srcPath -` piped into a
+// symlink-safe tar extractor. destDir is srcPath's parent: the tar stream
+// `docker cp` produces for a directory is already rooted at that
+// directory's leaf name, so extracting into its parent lands it at
+// destDir/<leaf>, matching `docker cp CONTAINER:srcPath destDir` semantics.
+// Unlike copyIn, the source side of a `docker cp` can't be created on
+// demand: if the image never created srcPath (e.g. a pipeline that writes
+// no output), it is treated as an empty directory rather than failing the
+// testcase.
+func copyOut(containerID, srcPath, destDir string) error {
+	cmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", containerID, srcPath), "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	extractErr := extractTar(pipe, destDir)
+
+	if err := cmd.Wait(); err != nil {
+		if noSuchPath(stderr.String()) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return extractErr
+}
+
+// noSuchPath reports whether a `docker cp` stderr indicates the source
+// path doesn't exist in the container, as opposed to some other failure
+// (container gone, daemon unreachable, etc.) that should still be surfaced.
+func noSuchPath(stderr string) bool {
+	return strings.Contains(stderr, "No such container:path") ||
+		strings.Contains(stderr, "no such file or directory") ||
+		strings.Contains(stderr, "could not find the file")
+}
+
+// startAndWait starts the container under ctx and blocks until it exits,
+// capturing its stdout/stderr (mirroring them to teeStdout/teeStderr too,
+// if non-nil) and returning its exit code alongside the captured output.
+func startAndWait(ctx context.Context, containerID string, teeStdout, teeStderr io.Writer) (Result, error) {
+	var stdoutBuf, stderrBuf bytes.Buffer
+
+	start := exec.CommandContext(ctx, "docker", "start", "-a", containerID)
+	start.Stdout = teeWriter(&stdoutBuf, teeStdout)
+	start.Stderr = teeWriter(&stderrBuf, teeStderr)
+	runErr := start.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return Result{}, fmt.Errorf("container timed out: %w", ctx.Err())
+	}
+
+	out, err := exec.Command("docker", "inspect", "-f", "{{.State.ExitCode}}", containerID).Output()
+	if err != nil {
+		return Result{}, err
+	}
+
+	exitCode, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{ExitCode: exitCode, Stdout: stdoutBuf.String(), Stderr: stderrBuf.String()}
+	if exitCode == 0 && runErr != nil {
+		return result, runErr
+	}
+	return result, nil
+}
+
+// teeWriter returns a writer that always records into buf and additionally
+// mirrors into extra, if non-nil.
+func teeWriter(buf *bytes.Buffer, extra io.Writer) io.Writer {
+	if extra == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, extra)
+}
+
+// removeContainer force-removes the container, best-effort; failures are
+// not surfaced since the testcase result has already been determined.
+func removeContainer(containerID string) {
+	_ = exec.Command("docker", "rm", "-f", containerID).Run()
+}